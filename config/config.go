@@ -1740,6 +1740,18 @@ func (c *Config) buildSerializer(tbl *ast.Table) (serializers.Serializer, error)
 	c.getFieldBool(tbl, "prometheus_export_timestamp", &sc.PrometheusExportTimestamp)
 	c.getFieldBool(tbl, "prometheus_sort_metrics", &sc.PrometheusSortMetrics)
 	c.getFieldBool(tbl, "prometheus_string_as_label", &sc.PrometheusStringAsLabel)
+	c.getFieldBool(tbl, "prometheus_string_as_info", &sc.PrometheusStringAsInfo)
+	c.getFieldStringSlice(tbl, "prometheus_info_fields", &sc.PrometheusInfoFields)
+	c.getFieldInt(tbl, "prometheus_info_max_value_length", &sc.PrometheusInfoMaxValueLength)
+	c.getFieldInt(tbl, "prometheus_max_labels_per_series", &sc.PrometheusMaxLabelsPerSeries)
+	c.getFieldString(tbl, "prometheus_max_labels_policy", &sc.PrometheusMaxLabelsPolicy)
+	c.getFieldStringSlice(tbl, "prometheus_label_trim_order", &sc.PrometheusLabelTrimOrder)
+	c.getFieldString(tbl, "prometheus_large_int_policy", &sc.PrometheusLargeIntPolicy)
+	c.getFieldDuration(tbl, "prometheus_max_future_skew", &sc.PrometheusMaxFutureSkew)
+	c.getFieldString(tbl, "prometheus_future_timestamp_policy", &sc.PrometheusFutureTimestampPolicy)
+	c.getFieldBool(tbl, "prometheus_counter_total_suffix", &sc.PrometheusCounterTotalSuffix)
+	c.getFieldString(tbl, "prometheus_duplicate_label_policy", &sc.PrometheusDuplicateLabelPolicy)
+	c.getFieldString(tbl, "prometheus_name_label_policy", &sc.PrometheusNameLabelPolicy)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
@@ -1795,7 +1807,7 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"json_string_fields", "json_time_format", "json_time_key", "json_timestamp_format", "json_timestamp_units", "json_timezone", "json_v2",
 		"lvm", "metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
 		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
-		"prefix", "prometheus_export_timestamp", "prometheus_ignore_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
+		"prefix", "prometheus_counter_total_suffix", "prometheus_duplicate_label_policy", "prometheus_export_timestamp", "prometheus_future_timestamp_policy", "prometheus_ignore_timestamp", "prometheus_info_fields", "prometheus_info_max_value_length", "prometheus_label_trim_order", "prometheus_large_int_policy", "prometheus_max_future_skew", "prometheus_max_labels_per_series", "prometheus_max_labels_policy", "prometheus_name_label_policy", "prometheus_sort_metrics", "prometheus_string_as_info", "prometheus_string_as_label",
 		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
 		"value_field_name", "wavefront_source_override", "wavefront_use_strict", "wavefront_disable_prefix_conversion",
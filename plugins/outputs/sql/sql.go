@@ -1,9 +1,11 @@
 package sql
 
 import (
+	"context"
 	gosql "database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	//Register sql drivers
 	_ "github.com/denisenkom/go-mssqldb"   // mssql (sql server)
@@ -12,7 +14,9 @@ import (
 	_ "github.com/snowflakedb/gosnowflake" // snowflake
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 type ConvertStruct struct {
@@ -23,28 +27,60 @@ type ConvertStruct struct {
 	Defaultvalue string
 	Unsigned     string
 	Bool         string
+
+	// ConversionStyle defines how the unsigned type is built, either by
+	// appending Unsigned to Integer ("unsigned_suffix") or by using Unsigned
+	// as is ("literal").
+	ConversionStyle string `toml:"conversion_style"`
 }
 
 type SQL struct {
 	Driver              string
 	DataSourceName      string
 	TimestampColumn     string
+	MetricTypeColumn    string
 	TableTemplate       string
 	TableExistsTemplate string
 	InitSQL             string `toml:"init_sql"`
 	Convert             ConvertStruct
 
-	db     *gosql.DB
-	Log    telegraf.Logger `toml:"-"`
-	tables map[string]bool
+	MaxOpenConnections    int             `toml:"max_open_connections"`
+	MaxIdleConnections    int             `toml:"max_idle_connections"`
+	ConnectionMaxLifetime config.Duration `toml:"connection_max_lifetime"`
+
+	Timeout config.Duration `toml:"timeout"`
+
+	// StatsTags are added to the internal statistics of the output to tell
+	// several instances apart.
+	StatsTags map[string]string `toml:"stats_tags"`
+
+	db  *gosql.DB
+	Log telegraf.Logger `toml:"-"`
+
+	// tables holds the tags stored in each known table, fields named like
+	// one of them go to a suffixed column.
+	tables map[string]map[string]bool
+
+	writeErrors   selfstat.Stat
+	tablesCreated selfstat.Stat
 }
 
 func (p *SQL) Connect() error {
+	switch p.Convert.ConversionStyle {
+	case "", "unsigned_suffix", "literal":
+	default:
+		return fmt.Errorf("invalid conversion_style %q", p.Convert.ConversionStyle)
+	}
+
 	db, err := gosql.Open(p.Driver, p.DataSourceName)
 	if err != nil {
 		return err
 	}
 
+	db.SetMaxOpenConns(p.MaxOpenConnections)
+	db.SetMaxIdleConns(p.MaxIdleConnections)
+	db.SetConnMaxLifetime(time.Duration(p.ConnectionMaxLifetime))
+
 	err = db.Ping()
 	if err != nil {
 		return err
@@ -58,7 +94,14 @@ func (p *SQL) Connect() error {
 	}
 
 	p.db = db
-	p.tables = make(map[string]bool)
+	p.tables = make(map[string]map[string]bool)
+
+	tags := map[string]string{"driver": p.Driver}
+	for k, v := range p.StatsTags {
+		tags[k] = v
+	}
+	p.writeErrors = selfstat.Register("sql", "write_errors", tags)
+	p.tablesCreated = selfstat.Register("sql", "tables_created", tags)
 
 	return nil
 }
@@ -99,7 +142,11 @@ func (p *SQL) deriveDatatype(value interface{}) string {
 	case int64:
 		datatype = p.Convert.Integer
 	case uint64:
-		datatype = fmt.Sprintf("%s %s", p.Convert.Integer, p.Convert.Unsigned)
+		if p.Convert.ConversionStyle == "literal" {
+			datatype = p.Convert.Unsigned
+		} else {
+			datatype = fmt.Sprintf("%s %s", p.Convert.Integer, p.Convert.Unsigned)
+		}
 	case float64:
 		datatype = p.Convert.Real
 	case string:
@@ -127,6 +174,10 @@ var sampleConfig = `
   ## Timestamp column name
   # timestamp_column = "timestamp"
 
+  ## Metric type column name
+  ## Stores the type of the metric, such as "counter" or "gauge", when set.
+  # metric_type_column = ""
+
   ## Table creation template
   ## Available template variables:
   ##  {TABLE} - table name as a quoted identifier
@@ -142,6 +193,25 @@ var sampleConfig = `
   ## Initialization SQL
   # init_sql = ""
 
+  ## Maximum time a write may take, including table creation; statements
+  ## still running when it expires are canceled.  0 means no limit.
+  # timeout = "5s"
+
+  ## Maximum number of open connections to the database, 0 means unlimited
+  # max_open_connections = 0
+
+  ## Maximum number of idle connections kept in the pool, 0 means no idle
+  ## connections are retained
+  # max_idle_connections = 2
+
+  ## Maximum amount of time a connection may be reused, 0 means forever
+  # connection_max_lifetime = "0s"
+
+  ## Tags added to the internal statistics of this output, to tell several
+  ## instances apart
+  # [outputs.sql.stats_tags]
+  #   instance = "primary"
+
   ## Metric type to SQL type conversion
   ## The values on the left are the data types Telegraf has and the values on
   ## the right are the data types Telegraf will use when sending to a database.
@@ -157,6 +227,10 @@ var sampleConfig = `
   #  timestamp            = "TIMESTAMP"
   #  defaultvalue         = "TEXT"
   #  unsigned             = "UNSIGNED"
+  #  bool                 = "BOOL"
+  #  ## How the unsigned type is built: "unsigned_suffix" appends unsigned to
+  #  ## the integer type, "literal" uses the unsigned type as is.
+  #  conversion_style     = "unsigned_suffix"
 `
 
 func (p *SQL) SampleConfig() string { return sampleConfig }
@@ -172,15 +246,20 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(p.TimestampColumn), p.Convert.Timestamp))
 	}
 
+	if p.MetricTypeColumn != "" {
+		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(p.MetricTypeColumn), p.Convert.Text))
+	}
+
 	for _, tag := range metric.TagList() {
 		//pk = append(pk, quoteIdent(tag.Key))
-		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(tag.Key), p.Convert.Text))
+		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(p.tagColumn(tag.Key)), p.Convert.Text))
 	}
 
+	tags := tagSet(metric)
 	var datatype string
 	for _, field := range metric.FieldList() {
 		datatype = p.deriveDatatype(field.Value)
-		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(field.Key), datatype))
+		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(p.fieldColumn(tags, field.Key)), datatype))
 	}
 
 	query := p.TableTemplate
@@ -192,6 +271,85 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 	return query
 }
 
+// metricType returns the name of a metric type as stored in the database.
+func metricType(tp telegraf.ValueType) string {
+	switch tp {
+	case telegraf.Counter:
+		return "counter"
+	case telegraf.Gauge:
+		return "gauge"
+	case telegraf.Summary:
+		return "summary"
+	case telegraf.Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// reservedColumn reports whether a column name is used by the plugin itself.
+func (p *SQL) reservedColumn(name string) bool {
+	return (p.TimestampColumn != "" && name == p.TimestampColumn) ||
+		(p.MetricTypeColumn != "" && name == p.MetricTypeColumn)
+}
+
+// tagColumn returns the column name for a tag.  A tag named like a column
+// used by the plugin gets a "_tag" suffix, as a table cannot have two
+// columns of the same name.
+func (p *SQL) tagColumn(key string) string {
+	if p.reservedColumn(key) {
+		return key + "_tag"
+	}
+	return key
+}
+
+// fieldColumn returns the column name for a field.  A field named like a tag
+// stored in the table or a column used by the plugin gets a "_field" suffix.
+// The tags of the table are used rather than the ones of the metric so a
+// field always goes to the same column.
+func (p *SQL) fieldColumn(tags map[string]bool, key string) string {
+	if tags[key] || p.reservedColumn(key) {
+		return key + "_field"
+	}
+	return key
+}
+
+// tagSet returns the tag keys of a metric.
+func tagSet(metric telegraf.Metric) map[string]bool {
+	tags := make(map[string]bool, len(metric.TagList()))
+	for _, tag := range metric.TagList() {
+		tags[tag.Key] = true
+	}
+	return tags
+}
+
+// tableTags returns the tags of an existing table that have a field of the
+// same name, as found from the columns named after the field.
+func (p *SQL) tableTags(ctx context.Context, tableName string) (map[string]bool, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT * FROM "+quoteIdent(tableName)+" WHERE 1=0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		names[column] = true
+	}
+	tags := make(map[string]bool)
+	for _, column := range columns {
+		if names[column+"_field"] {
+			tags[column] = true
+		}
+	}
+	return tags, nil
+}
+
 func (p *SQL) generateInsert(tablename string, columns []string) string {
 	var placeholders, quotedColumns []string
 	for _, column := range columns {
@@ -215,54 +373,105 @@ func (p *SQL) generateInsert(tablename string, columns []string) string {
 		strings.Join(placeholders, ","))
 }
 
-func (p *SQL) tableExists(tableName string) bool {
+func (p *SQL) tableExists(ctx context.Context, tableName string) bool {
 	stmt := strings.Replace(p.TableExistsTemplate, "{TABLE}", quoteIdent(tableName), -1)
 
-	_, err := p.db.Exec(stmt)
+	_, err := p.db.ExecContext(ctx, stmt)
 	return err == nil
 }
 
 func (p *SQL) Write(metrics []telegraf.Metric) error {
+	ctx := context.Background()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.Timeout))
+		defer cancel()
+	}
+
+	// Create missing tables before starting the transaction, DDL cannot be
+	// rolled back and implicitly commits on some databases.
 	for _, metric := range metrics {
 		tablename := metric.Name()
 
-		// create table if needed
-		if !p.tables[tablename] && !p.tableExists(tablename) {
-			createStmt := p.generateCreateTable(metric)
-			_, err := p.db.Exec(createStmt)
+		if _, ok := p.tables[tablename]; ok {
+			continue
+		}
+
+		if p.tableExists(ctx, tablename) {
+			tags, err := p.tableTags(ctx, tablename)
 			if err != nil {
+				p.writeErrors.Incr(1)
 				return err
 			}
-			p.tables[tablename] = true
+			p.tables[tablename] = tags
+			continue
+		}
+
+		createStmt := p.generateCreateTable(metric)
+		_, err := p.db.ExecContext(ctx, createStmt)
+		if err != nil {
+			p.writeErrors.Incr(1)
+			return err
 		}
+		p.tables[tablename] = tagSet(metric)
+		p.tablesCreated.Incr(1)
+	}
+
+	// Insert the batch in a single transaction so a failed write does not
+	// leave it partially written and duplicated when it is retried.
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		p.writeErrors.Incr(1)
+		return err
+	}
+
+	for _, metric := range metrics {
+		tablename := metric.Name()
 
 		var columns []string
 		var values []interface{}
 
 		if p.TimestampColumn != "" {
+			// Drivers store the wall clock of the time in columns without a
+			// time zone, so always bind it in UTC.
 			columns = append(columns, p.TimestampColumn)
-			values = append(values, metric.Time())
+			values = append(values, metric.Time().UTC())
+		}
+
+		if p.MetricTypeColumn != "" {
+			columns = append(columns, p.MetricTypeColumn)
+			values = append(values, metricType(metric.Type()))
 		}
 
 		for column, value := range metric.Tags() {
-			columns = append(columns, column)
+			columns = append(columns, p.tagColumn(column))
 			values = append(values, value)
 		}
 
+		tags := p.tables[tablename]
 		for column, value := range metric.Fields() {
-			columns = append(columns, column)
+			columns = append(columns, p.fieldColumn(tags, column))
 			values = append(values, value)
 		}
 
 		sql := p.generateInsert(tablename, columns)
-		_, err := p.db.Exec(sql, values...)
+		_, err := tx.ExecContext(ctx, sql, values...)
 
 		if err != nil {
 			// check if insert error was caused by column mismatch
 			p.Log.Errorf("Error during insert: %v, %v", err, sql)
+			if rerr := tx.Rollback(); rerr != nil {
+				p.Log.Errorf("Error during rollback: %v", rerr)
+			}
+			p.writeErrors.Incr(1)
 			return err
 		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		p.writeErrors.Incr(1)
+		return err
+	}
 	return nil
 }
 
@@ -275,6 +484,8 @@ func newSQL() *SQL {
 		TableTemplate:       "CREATE TABLE {TABLE}({COLUMNS})",
 		TableExistsTemplate: "SELECT 1 FROM {TABLE} LIMIT 1",
 		TimestampColumn:     "timestamp",
+		MaxIdleConnections:  2,
+		Timeout:             config.Duration(5 * time.Second),
 		Convert: ConvertStruct{
 			Integer:      "INT",
 			Real:         "DOUBLE",
@@ -283,6 +494,8 @@ func newSQL() *SQL {
 			Defaultvalue: "TEXT",
 			Unsigned:     "UNSIGNED",
 			Bool:         "BOOL",
+
+			ConversionStyle: "unsigned_suffix",
 		},
 	}
 }
@@ -29,6 +29,79 @@ func TestSqlCreateStatement(t *testing.T) {
 	}
 }
 
+func TestSqlCreateStatementTagFieldCollision(t *testing.T) {
+	p := newSQL()
+	m := testutil.MustMetric(
+		"metric_one",
+		map[string]string{
+			"host": "localhost",
+		},
+		map[string]interface{}{
+			"host": int64(1),
+		},
+		time.Unix(0, 0),
+	)
+
+	require.Equal(t,
+		`CREATE TABLE "metric_one"("timestamp" TIMESTAMP,"host" TEXT,"host_field" INT)`,
+		p.generateCreateTable(m),
+	)
+}
+
+func TestSqlCreateStatementTimestampCollision(t *testing.T) {
+	p := newSQL()
+	m := testutil.MustMetric(
+		"metric_one",
+		map[string]string{
+			"timestamp": "tag",
+		},
+		map[string]interface{}{
+			"timestamp": int64(1),
+		},
+		time.Unix(0, 0),
+	)
+
+	require.Equal(t,
+		`CREATE TABLE "metric_one"("timestamp" TIMESTAMP,"timestamp_tag" TEXT,"timestamp_field" INT)`,
+		p.generateCreateTable(m),
+	)
+}
+
+func TestSqlCreateStatementMetricType(t *testing.T) {
+	p := newSQL()
+	p.MetricTypeColumn = "metric_type"
+	m := stableMetric(
+		"metric_one",
+		[]telegraf.Tag{
+			{Key: "metric_type", Value: "tag"},
+		},
+		[]telegraf.Field{
+			{Key: "metric_type", Value: "field"},
+			{Key: "value", Value: int64(1)},
+		},
+		time.Unix(0, 0),
+		telegraf.Counter,
+	)
+
+	require.Equal(t,
+		`CREATE TABLE "metric_one"("timestamp" TIMESTAMP,"metric_type" TEXT,"metric_type_tag" TEXT,"metric_type_field" TEXT,"value" INT)`,
+		p.generateCreateTable(m),
+	)
+	require.Equal(t, "counter", metricType(m.Type()))
+}
+
+func TestSqlUnsignedDatatype(t *testing.T) {
+	p := newSQL()
+	require.Equal(t, "INT UNSIGNED", p.deriveDatatype(uint64(1)))
+
+	p.Convert.ConversionStyle = ""
+	require.Equal(t, "INT UNSIGNED", p.deriveDatatype(uint64(1)))
+
+	p.Convert.Unsigned = "NUMERIC"
+	p.Convert.ConversionStyle = "literal"
+	require.Equal(t, "NUMERIC", p.deriveDatatype(uint64(1)))
+}
+
 func TestSqlInsertStatement(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
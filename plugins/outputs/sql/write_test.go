@@ -0,0 +1,308 @@
+//go:build linux && amd64
+// +build linux,amd64
+
+package sql
+
+// These tests exercise Write against an in-process sqlite database so they
+// can run in short mode. The driver is imported here as sqlite.go only
+// registers it on platforms it never builds for.
+import (
+	gosql "database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite" // Register sqlite sql driver
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newSqlite(t *testing.T) *SQL {
+	p := newSQL()
+	p.Log = testutil.Logger{}
+	p.Driver = "sqlite"
+	p.DataSourceName = filepath.Join(t.TempDir(), "db")
+	// This version of the sqlite driver may interrupt a connection after
+	// its statement has finished once the context is canceled, so only use
+	// a timeout where the test needs one.
+	p.Timeout = 0
+	return p
+}
+
+func countRows(t *testing.T, p *SQL, table string) int {
+	var count int
+	require.NoError(t, p.db.QueryRow("SELECT count(*) FROM "+quoteIdent(table)).Scan(&count))
+	return count
+}
+
+func TestSqliteWriteRollback(t *testing.T) {
+	p := newSqlite(t)
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+		// The table is created from the first metric, so the insert of
+		// this one fails on the missing column.
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"other": int64(2),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	require.Error(t, p.Write(metrics))
+	require.Equal(t, 0, countRows(t, p, "cpu"))
+
+	require.NoError(t, p.Write(metrics[:1]))
+	require.Equal(t, 1, countRows(t, p, "cpu"))
+}
+
+func TestSqliteConversionStyle(t *testing.T) {
+	p := newSqlite(t)
+	p.Convert.ConversionStyle = ""
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.Close())
+
+	p = newSqlite(t)
+	p.Convert.ConversionStyle = "unsigned"
+	require.EqualError(t, p.Connect(), `invalid conversion_style "unsigned"`)
+}
+
+func TestSqliteConnectionPool(t *testing.T) {
+	p := newSqlite(t)
+	p.MaxOpenConnections = 3
+	p.MaxIdleConnections = 0
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	stats := p.db.Stats()
+	require.Equal(t, 3, stats.MaxOpenConnections)
+	// Without idle connections the one used by Connect is closed on return.
+	require.Equal(t, 0, stats.Idle)
+	require.Greater(t, stats.MaxIdleClosed, int64(0))
+}
+
+func TestSqliteWriteTimeUTC(t *testing.T) {
+	p := newSqlite(t)
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	tm := time.Date(2021, 5, 17, 16, 4, 45, 0, time.FixedZone("MDT", -6*60*60))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": int64(1),
+			},
+			tm,
+		),
+	}))
+
+	// The sqlite driver stores times as the output of time.Time.String,
+	// read it back as text so the driver does not parse it.
+	var stored string
+	require.NoError(t, p.db.QueryRow(`SELECT CAST("timestamp" AS TEXT) FROM "cpu"`).Scan(&stored))
+	require.Equal(t, "2021-05-17 22:04:45 +0000 UTC", stored)
+}
+
+func TestSqliteWriteTimeout(t *testing.T) {
+	p := newSqlite(t)
+	p.Timeout = config.Duration(100 * time.Millisecond)
+	// Use a query that never finishes in place of the table creation.
+	p.TableTemplate = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT count(*) FROM c"
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.Write([]telegraf.Metric{
+			testutil.MustMetric(
+				"cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"value": int64(1),
+				},
+				time.Unix(0, 0),
+			),
+		})
+	}()
+
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "write was not canceled")
+	}
+}
+
+func TestSqliteWriteTimestampCollision(t *testing.T) {
+	p := newSqlite(t)
+	p.TimestampColumn = "time"
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"time": "tag",
+			},
+			map[string]interface{}{
+				"time": int64(42),
+			},
+			time.Unix(0, 0),
+		),
+	}))
+
+	var tag string
+	var field int64
+	require.NoError(t, p.db.QueryRow(`SELECT "time_tag", "time_field" FROM "cpu"`).Scan(&tag, &field))
+	require.Equal(t, "tag", tag)
+	require.Equal(t, int64(42), field)
+}
+
+func TestSqliteWriteTagFieldCollision(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"exec",
+			map[string]string{
+				"host": "a",
+			},
+			map[string]interface{}{
+				"host": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+		// Without the tag the field still goes to the column of the table.
+		testutil.MustMetric(
+			"exec",
+			map[string]string{},
+			map[string]interface{}{
+				"host": int64(2),
+			},
+			time.Unix(1, 0),
+		),
+	}
+
+	p := newSqlite(t)
+	require.NoError(t, p.Connect())
+	require.NoError(t, p.Write(metrics))
+
+	readRows := func(p *SQL) ([]gosql.NullString, []int64) {
+		rows, err := p.db.Query(`SELECT "host", "host_field" FROM "exec" ORDER BY "host_field"`)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var tags []gosql.NullString
+		var fields []int64
+		for rows.Next() {
+			var tag gosql.NullString
+			var field int64
+			require.NoError(t, rows.Scan(&tag, &field))
+			tags = append(tags, tag)
+			fields = append(fields, field)
+		}
+		require.NoError(t, rows.Err())
+		return tags, fields
+	}
+
+	tags, fields := readRows(p)
+	require.Equal(t, []gosql.NullString{{String: "a", Valid: true}, {}}, tags)
+	require.Equal(t, []int64{1, 2}, fields)
+	require.NoError(t, p.Close())
+
+	// A new instance finds the tags of the existing table from its columns.
+	p2 := newSqlite(t)
+	p2.DataSourceName = p.DataSourceName
+	require.NoError(t, p2.Connect())
+	defer p2.Close()
+	require.NoError(t, p2.Write(metrics[1:]))
+
+	tags, fields = readRows(p2)
+	require.Equal(t, []gosql.NullString{{String: "a", Valid: true}, {}, {}}, tags)
+	require.Equal(t, []int64{1, 2, 2}, fields)
+}
+
+func TestSqliteWriteMetricType(t *testing.T) {
+	p := newSqlite(t)
+	p.MetricTypeColumn = "metric_type"
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"metric_type": "tag",
+			},
+			map[string]interface{}{
+				"value": int64(1),
+			},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}))
+
+	var tp, tag string
+	require.NoError(t, p.db.QueryRow(`SELECT "metric_type", "metric_type_tag" FROM "cpu"`).Scan(&tp, &tag))
+	require.Equal(t, "counter", tp)
+	require.Equal(t, "tag", tag)
+}
+
+func TestSqliteWriteStats(t *testing.T) {
+	p := newSqlite(t)
+	p.StatsTags = map[string]string{"instance": t.Name()}
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	require.Equal(t, map[string]string{"driver": "sqlite", "instance": t.Name()}, p.writeErrors.Tags())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{
+				"value": int64(2),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, p.Write(metrics))
+	require.Equal(t, int64(2), p.tablesCreated.Get())
+	require.Equal(t, int64(0), p.writeErrors.Get())
+
+	require.Error(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"other": int64(1),
+			},
+			time.Unix(1, 0),
+		),
+	}))
+	require.Equal(t, int64(2), p.tablesCreated.Get())
+	require.Equal(t, int64(1), p.writeErrors.Get())
+}
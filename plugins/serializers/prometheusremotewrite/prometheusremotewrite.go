@@ -1,18 +1,20 @@
 package prometheusremotewrite
 
 import (
-	"bytes"
 	"fmt"
 	"hash/fnv"
+	"log"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/snappy"
 	"github.com/prometheus/prometheus/prompb"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 )
 
@@ -32,19 +34,179 @@ type StringHandling int
 const (
 	DiscardStrings StringHandling = iota
 	StringAsLabel
+	StringAsInfo
 )
 
+// LargeIntHandling defines how to process integers that cannot be exactly
+// represented as a float64 sample value.
+type LargeIntHandling int
+
+const (
+	ConvertLargeInts LargeIntHandling = iota
+	DropLargeInts
+)
+
+// FutureTimestampHandling defines how to process metrics with a timestamp
+// too far in the future.
+type FutureTimestampHandling int
+
+const (
+	ClampFutureTimestamps FutureTimestampHandling = iota
+	DropFutureTimestamps
+)
+
+// MaxLabelsHandling defines how to process series with more labels than
+// allowed.
+type MaxLabelsHandling int
+
+const (
+	DropSeries MaxLabelsHandling = iota
+	TrimLabels
+)
+
+// NameLabelHandling defines how to process tags and string fields whose name
+// collides with the metric name label.
+type NameLabelHandling int
+
+const (
+	StripNameLabel NameLabelHandling = iota
+	RenameNameLabel
+)
+
+// renamedNameLabel is the label name used for tags and fields named
+// "__name__", following the prefix Prometheus uses for conflicting labels.
+const renamedNameLabel = "exported___name__"
+
+// DuplicateLabelHandling defines how to process a series with several labels
+// of the same name, which can happen when sanitizing tag keys.
+type DuplicateLabelHandling int
+
+const (
+	KeepLastDuplicateLabel DuplicateLabelHandling = iota
+	DropDuplicateLabels
+)
+
+// maxExactInt is the largest integer magnitude a float64 can represent
+// without losing precision.
+const maxExactInt = 1 << 53
+
 type FormatConfig struct {
-	MetricSortOrder MetricSortOrder
-	StringHandling  StringHandling
+	MetricSortOrder  MetricSortOrder
+	StringHandling   StringHandling
+	LargeIntHandling LargeIntHandling
+
+	// InfoFields limits the string fields written as info series to the
+	// ones matching these patterns, empty means all fields.  Values longer
+	// than InfoMaxValueLength are skipped, zero means no limit.  Every
+	// distinct value creates a new info series, so both keep free-text
+	// fields from growing the number of series without bound.
+	InfoFields         []string
+	InfoMaxValueLength int
+
+	// CounterTotalSuffix appends "_total" to the name of counters, as
+	// expected by the Prometheus naming conventions.
+	CounterTotalSuffix bool
+
+	// MaxFutureSkew is how far a metric timestamp may be ahead of the
+	// current time before FutureTimestampHandling applies.  Zero disables
+	// the check.
+	MaxFutureSkew           time.Duration
+	FutureTimestampHandling FutureTimestampHandling
+
+	// MaxLabelsPerSeries is the maximum number of labels, including the
+	// metric name, a series may have.  Zero means no limit.  Series over the
+	// limit are dropped, unless MaxLabelsHandling is TrimLabels; then the
+	// labels listed in LabelTrimOrder are removed first to last until the
+	// series fit, and only series still over the limit are dropped.
+	MaxLabelsPerSeries int
+	MaxLabelsHandling  MaxLabelsHandling
+	LabelTrimOrder     []string
+
+	// DuplicateLabelHandling resolves labels that share a name after
+	// sanitization.
+	DuplicateLabelHandling DuplicateLabelHandling
+
+	// NameLabelHandling resolves tags and string fields named "__name__".
+	NameLabelHandling NameLabelHandling
+}
+
+// batchStats counts the metrics altered or discarded while serializing a
+// batch, so they can be logged once per batch.
+type batchStats struct {
+	clampedTimestamps       int
+	droppedTimestamps       int
+	convertedLargeInts      int
+	droppedLargeInts        int
+	strippedNameLabels      int
+	renamedNameLabels       int
+	trimmedLabelSets        int
+	droppedSeries           int
+	resolvedDuplicateLabels int
+	droppedDuplicateLabels  int
+	skippedInfoValues       int
+}
+
+func (st *batchStats) log() {
+	if st.clampedTimestamps > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] clamped the timestamp of %d metrics too far in the future", st.clampedTimestamps)
+	}
+	if st.droppedTimestamps > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] dropped %d metrics with a timestamp too far in the future", st.droppedTimestamps)
+	}
+	if st.convertedLargeInts > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] lost precision converting %d integers larger than 2^53", st.convertedLargeInts)
+	}
+	if st.droppedLargeInts > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] dropped %d integers larger than 2^53", st.droppedLargeInts)
+	}
+	if st.strippedNameLabels > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] discarded %d tags or fields named __name__", st.strippedNameLabels)
+	}
+	if st.renamedNameLabels > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] renamed %d tags or fields named __name__ to %s", st.renamedNameLabels, renamedNameLabel)
+	}
+	if st.resolvedDuplicateLabels > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] discarded %d labels with a duplicate name after sanitizing", st.resolvedDuplicateLabels)
+	}
+	if st.droppedDuplicateLabels > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] dropped %d metrics with duplicate label names after sanitizing", st.droppedDuplicateLabels)
+	}
+	if st.skippedInfoValues > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] skipped %d string fields too long for an info series", st.skippedInfoValues)
+	}
+	if st.trimmedLabelSets > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] removed labels from %d metrics exceeding the label limit", st.trimmedLabelSets)
+	}
+	if st.droppedSeries > 0 {
+		log.Printf("W! Serializer [prometheusremotewrite] dropped %d series exceeding the label limit", st.droppedSeries)
+	}
+}
+
+// writeRequest holds the buffers reused between calls to SerializeBatch.
+type writeRequest struct {
+	req  prompb.WriteRequest
+	data []byte
 }
 
 type Serializer struct {
-	config FormatConfig
+	config     FormatConfig
+	infoFields filter.Filter
+	now        func() time.Time
+	pool       sync.Pool
 }
 
 func NewSerializer(config FormatConfig) (*Serializer, error) {
-	s := &Serializer{config: config}
+	infoFields, err := filter.Compile(config.InfoFields)
+	if err != nil {
+		return nil, fmt.Errorf("compiling info fields failed: %v", err)
+	}
+
+	s := &Serializer{config: config, infoFields: infoFields, now: time.Now}
+	s.pool = sync.Pool{
+		New: func() interface{} {
+			return new(writeRequest)
+		},
+	}
 	return s, nil
 }
 
@@ -53,10 +215,28 @@ func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 }
 
 func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
-	var buf bytes.Buffer
-	var entries = make(map[MetricKey]prompb.TimeSeries)
+	// Most fields produce a single series, so the number of fields is a
+	// good estimate for the number of series in the batch.
+	var estimate int
 	for _, metric := range metrics {
-		commonLabels := s.createLabels(metric)
+		estimate += len(metric.FieldList())
+	}
+
+	var stats batchStats
+	defer stats.log()
+
+	var entries = make(map[MetricKey]prompb.TimeSeries, estimate)
+	for _, metric := range metrics {
+		ts, ok := s.sampleTime(metric.Time(), &stats)
+		if !ok {
+			continue
+		}
+
+		commonLabels, ok := s.createLabels(metric, &stats)
+		if !ok {
+			continue
+		}
+
 		var metrickey MetricKey
 		var promts prompb.TimeSeries
 		for _, field := range metric.FieldList() {
@@ -65,26 +245,36 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 			if !ok {
 				continue
 			}
+			if s.config.CounterTotalSuffix && metric.Type() == telegraf.Counter && !strings.HasSuffix(metricName, "_total") {
+				metricName += "_total"
+			}
 			switch metric.Type() {
 			case telegraf.Counter:
 				fallthrough
 			case telegraf.Gauge:
 				fallthrough
 			case telegraf.Untyped:
-				value, ok := prometheus.SampleValue(field.Value)
+				if v, isString := field.Value.(string); isString && s.config.StringHandling == StringAsInfo {
+					metrickey, promts, ok = s.getInfoTS(metric.Name(), field.Key, v, commonLabels, ts, &stats)
+					if !ok {
+						continue
+					}
+					break
+				}
+				value, ok := s.sampleValue(field.Value, &stats)
 				if !ok {
 					continue
 				}
-				metrickey, promts = getPromTS(metricName, commonLabels, value, metric.Time())
+				metrickey, promts = getPromTS(metricName, commonLabels, value, ts)
 			case telegraf.Histogram:
 				switch {
 				case strings.HasSuffix(field.Key, "_bucket"):
 					// if bucket only, init sum, count, inf
-					metrickeysum, promtssum := getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, float64(0), metric.Time())
+					metrickeysum, promtssum := getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, float64(0), ts)
 					if _, ok = entries[metrickeysum]; !ok {
 						entries[metrickeysum] = promtssum
 					}
-					metrickeycount, promtscount := getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(0), metric.Time())
+					metrickeycount, promtscount := getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(0), ts)
 					if _, ok = entries[metrickeycount]; !ok {
 						entries[metrickeycount] = promtscount
 					}
@@ -94,7 +284,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 						Name:  "le",
 						Value: "+Inf",
 					})
-					metrickeyinf, promtsinf := getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(0), metric.Time())
+					metrickeyinf, promtsinf := getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(0), ts)
 					if _, ok = entries[metrickeyinf]; !ok {
 						entries[metrickeyinf] = promtsinf
 					}
@@ -118,14 +308,14 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 						Name:  "le",
 						Value: fmt.Sprint(bound),
 					})
-					metrickey, promts = getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(count), metric.Time())
+					metrickey, promts = getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(count), ts)
 				case strings.HasSuffix(field.Key, "_sum"):
 					sum, ok := prometheus.SampleSum(field.Value)
 					if !ok {
 						continue
 					}
 
-					metrickey, promts = getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, sum, metric.Time())
+					metrickey, promts = getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, sum, ts)
 				case strings.HasSuffix(field.Key, "_count"):
 					count, ok := prometheus.SampleCount(field.Value)
 					if !ok {
@@ -139,12 +329,12 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 						Name:  "le",
 						Value: "+Inf",
 					})
-					metrickeyinf, promtsinf := getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(count), metric.Time())
+					metrickeyinf, promtsinf := getPromTS(fmt.Sprintf("%s_bucket", metricName), labels, float64(count), ts)
 					if minf, ok := entries[metrickeyinf]; !ok || minf.Samples[0].Value == 0 {
 						entries[metrickeyinf] = promtsinf
 					}
 
-					metrickey, promts = getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(count), metric.Time())
+					metrickey, promts = getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(count), ts)
 				default:
 					continue
 				}
@@ -156,14 +346,14 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 						continue
 					}
 
-					metrickey, promts = getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, sum, metric.Time())
+					metrickey, promts = getPromTS(fmt.Sprintf("%s_sum", metricName), commonLabels, sum, ts)
 				case strings.HasSuffix(field.Key, "_count"):
 					count, ok := prometheus.SampleCount(field.Value)
 					if !ok {
 						continue
 					}
 
-					metrickey, promts = getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(count), metric.Time())
+					metrickey, promts = getPromTS(fmt.Sprintf("%s_count", metricName), commonLabels, float64(count), ts)
 				default:
 					quantileTag, ok := metric.GetTag("quantile")
 					if !ok {
@@ -173,7 +363,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 					if err != nil {
 						continue
 					}
-					value, ok := prometheus.SampleValue(field.Value)
+					value, ok := s.sampleValue(field.Value, &stats)
 					if !ok {
 						continue
 					}
@@ -184,7 +374,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 						Name:  "quantile",
 						Value: fmt.Sprint(quantile),
 					})
-					metrickey, promts = getPromTS(metricName, labels, value, metric.Time())
+					metrickey, promts = getPromTS(metricName, labels, value, ts)
 				}
 			default:
 				return nil, fmt.Errorf("unknown type %v", metric.Type())
@@ -195,7 +385,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 			// sample then we can skip over it.
 			m, ok := entries[metrickey]
 			if ok {
-				if metric.Time().Before(time.Unix(m.Samples[0].Timestamp, 0)) {
+				if promts.Samples[0].Timestamp < m.Samples[0].Timestamp {
 					continue
 				}
 			}
@@ -203,11 +393,16 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 		}
 	}
 
-	var promTS = make([]prompb.TimeSeries, len(entries))
-	var i int
+	wr := s.pool.Get().(*writeRequest)
+	defer s.release(wr)
+
+	promTS := wr.req.Timeseries[:0]
 	for _, promts := range entries {
-		promTS[i] = promts
-		i++
+		if s.config.MaxLabelsPerSeries > 0 && len(promts.Labels) > s.config.MaxLabelsPerSeries {
+			stats.droppedSeries++
+			continue
+		}
+		promTS = append(promTS, promts)
 	}
 
 	if s.config.MetricSortOrder == SortMetrics {
@@ -234,14 +429,72 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 			return false
 		})
 	}
-	pb := &prompb.WriteRequest{Timeseries: promTS}
-	data, err := pb.Marshal()
+	wr.req.Timeseries = promTS
+	size := wr.req.Size()
+	if cap(wr.data) < size {
+		wr.data = make([]byte, size)
+	}
+	n, err := wr.req.MarshalToSizedBuffer(wr.data[:size])
 	if err != nil {
 		return nil, fmt.Errorf("unable to marshal protobuf: %v", err)
 	}
-	encoded := snappy.Encode(nil, data)
-	buf.Write(encoded) //nolint:revive // from buffer.go: "err is always nil"
-	return buf.Bytes(), nil
+	return snappy.Encode(nil, wr.data[:n]), nil
+}
+
+// release returns the buffers of a write request to the pool.  The series
+// are cleared so the pool does not keep the labels of the batch alive.
+func (s *Serializer) release(wr *writeRequest) {
+	for i := range wr.req.Timeseries {
+		wr.req.Timeseries[i] = prompb.TimeSeries{}
+	}
+	wr.req.Timeseries = wr.req.Timeseries[:0]
+	s.pool.Put(wr)
+}
+
+// sampleTime returns the timestamp to use for the samples of a metric.
+// Timestamps too far in the future are clamped or rejected as configured.
+func (s *Serializer) sampleTime(t time.Time, stats *batchStats) (time.Time, bool) {
+	if s.config.MaxFutureSkew <= 0 {
+		return t, true
+	}
+
+	limit := s.now().Add(s.config.MaxFutureSkew)
+	if !t.After(limit) {
+		return t, true
+	}
+
+	if s.config.FutureTimestampHandling == DropFutureTimestamps {
+		stats.droppedTimestamps++
+		return t, false
+	}
+	stats.clampedTimestamps++
+	return limit, true
+}
+
+// sampleValue converts a field value into a sample value.  Integers beyond
+// the exact integer range of a float64 are counted, and rejected if
+// configured to do so.
+func (s *Serializer) sampleValue(value interface{}, stats *batchStats) (float64, bool) {
+	if isLargeInt(value) {
+		if s.config.LargeIntHandling == DropLargeInts {
+			stats.droppedLargeInts++
+			return 0, false
+		}
+		stats.convertedLargeInts++
+	}
+	return prometheus.SampleValue(value)
+}
+
+// isLargeInt reports whether value is an integer that cannot be represented
+// exactly as a float64.
+func isLargeInt(value interface{}) bool {
+	switch v := value.(type) {
+	case int64:
+		return v > maxExactInt || v < -maxExactInt
+	case uint64:
+		return v > maxExactInt
+	}
+	return false
 }
 
 func hasLabel(name string, labels []prompb.Label) bool {
@@ -253,7 +506,7 @@ func hasLabel(name string, labels []prompb.Label) bool {
 	return false
 }
 
-func (s *Serializer) createLabels(metric telegraf.Metric) []prompb.Label {
+func (s *Serializer) createLabels(metric telegraf.Metric, stats *batchStats) ([]prompb.Label, bool) {
 	labels := make([]prompb.Label, 0, len(metric.TagList()))
 	for _, tag := range metric.TagList() {
 		// Ignore special tags for histogram and summary types.
@@ -268,7 +521,7 @@ func (s *Serializer) createLabels(metric telegraf.Metric) []prompb.Label {
 			}
 		}
 
-		name, ok := prometheus.SanitizeLabelName(tag.Key)
+		name, ok := s.labelName(tag.Key, stats)
 		if !ok {
 			continue
 		}
@@ -281,39 +534,139 @@ func (s *Serializer) createLabels(metric telegraf.Metric) []prompb.Label {
 		labels = append(labels, prompb.Label{Name: name, Value: tag.Value})
 	}
 
-	if s.config.StringHandling != StringAsLabel {
+	if s.config.StringHandling == StringAsLabel {
+		for _, field := range metric.FieldList() {
+			value, ok := field.Value.(string)
+			if !ok {
+				continue
+			}
+
+			name, ok := s.labelName(field.Key, stats)
+			if !ok {
+				continue
+			}
+
+			// If there is a tag with the same name as the string field,
+			// discard the field and use the tag instead.
+			if hasLabel(name, labels) {
+				continue
+			}
+
+			labels = append(labels, prompb.Label{Name: name, Value: value})
+		}
+	}
+
+	// Sanitizing may change the order of the tag keys and map distinct keys,
+	// such as "a.b" and "a_b", to the same label name.  A stable sort keeps
+	// the duplicates in tag order so resolving them is deterministic.  Most
+	// labels are already sorted, so skip the sort and its allocations then.
+	if !labelsSorted(labels) {
+		sort.SliceStable(labels, func(i, j int) bool {
+			return labels[i].Name < labels[j].Name
+		})
+	}
+
+	labels, ok := s.dedupLabels(labels, stats)
+	if !ok {
+		return nil, false
+	}
+	return s.trimLabels(metric, labels, stats), true
+}
+
+// trimLabels removes labels in the configured trim order until the series of
+// a metric fit within the label limit.  Room is kept for the labels added to
+// each series: the metric name, "le" or "quantile" for histograms and
+// summaries, and the field of info series.
+func (s *Serializer) trimLabels(metric telegraf.Metric, labels []prompb.Label, stats *batchStats) []prompb.Label {
+	if s.config.MaxLabelsHandling != TrimLabels || s.config.MaxLabelsPerSeries <= 0 {
 		return labels
 	}
 
-	addedFieldLabel := false
-	for _, field := range metric.FieldList() {
-		value, ok := field.Value.(string)
-		if !ok {
-			continue
+	limit := s.config.MaxLabelsPerSeries - 1
+	switch metric.Type() {
+	case telegraf.Histogram, telegraf.Summary:
+		limit--
+	default:
+		if s.config.StringHandling == StringAsInfo && hasStringField(metric) {
+			limit--
 		}
+	}
 
-		name, ok := prometheus.SanitizeLabelName(field.Key)
-		if !ok {
-			continue
+	trimmed := false
+	for _, name := range s.config.LabelTrimOrder {
+		if len(labels) <= limit {
+			break
+		}
+		for i := range labels {
+			if labels[i].Name == name {
+				labels = append(labels[:i], labels[i+1:]...)
+				trimmed = true
+				break
+			}
 		}
+	}
+	if trimmed {
+		stats.trimmedLabelSets++
+	}
+	return labels
+}
 
-		// If there is a tag with the same name as the string field, discard
-		// the field and use the tag instead.
-		if hasLabel(name, labels) {
-			continue
+func hasStringField(metric telegraf.Metric) bool {
+	for _, field := range metric.FieldList() {
+		if _, ok := field.Value.(string); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		labels = append(labels, prompb.Label{Name: name, Value: value})
-		addedFieldLabel = true
+// labelName returns the label name for a tag or field key.  The metric name
+// label is generated from the measurement and the field key, so keys that
+// would collide with it are stripped or renamed as configured.
+func (s *Serializer) labelName(key string, stats *batchStats) (string, bool) {
+	name, ok := prometheus.SanitizeLabelName(key)
+	if !ok {
+		return "", false
+	}
+	if name != "__name__" {
+		return name, true
 	}
 
-	if addedFieldLabel {
-		sort.Slice(labels, func(i, j int) bool {
-			return labels[i].Name < labels[j].Name
-		})
+	if s.config.NameLabelHandling == RenameNameLabel {
+		stats.renamedNameLabels++
+		return renamedNameLabel, true
 	}
+	stats.strippedNameLabels++
+	return "", false
+}
 
-	return labels
+// dedupLabels removes labels sharing a name from a sorted label set, keeping
+// the last one.  If duplicates are configured to be dropped, false is
+// returned when any are found.
+func (s *Serializer) dedupLabels(labels []prompb.Label, stats *batchStats) ([]prompb.Label, bool) {
+	deduped := labels[:0]
+	for i, label := range labels {
+		if i+1 < len(labels) && labels[i+1].Name == label.Name {
+			if s.config.DuplicateLabelHandling == DropDuplicateLabels {
+				stats.droppedDuplicateLabels++
+				return nil, false
+			}
+			stats.resolvedDuplicateLabels++
+			continue
+		}
+		deduped = append(deduped, label)
+	}
+	return deduped, true
+}
+
+// labelsSorted reports whether the labels are sorted by name.
+func labelsSorted(labels []prompb.Label) bool {
+	for i := 1; i < len(labels); i++ {
+		if labels[i].Name < labels[i-1].Name {
+			return false
+		}
+	}
+	return true
 }
 
 func MakeMetricKey(labels []prompb.Label) MetricKey {
@@ -341,3 +694,44 @@ func getPromTS(name string, labels []prompb.Label, value float64, ts time.Time)
 	})
 	return MakeMetricKey(labels), prompb.TimeSeries{Labels: labels, Samples: sample}
 }
+
+// getInfoTS creates an info-style series for a string field.  The field is
+// added as a label to a "<measurement>_info" series with a constant value of
+// one.  Each string field produces its own series so the cardinality of a
+// series is never the product of multiple string fields.  Only the fields
+// allowed by InfoFields and values within InfoMaxValueLength are used.
+func (s *Serializer) getInfoTS(measurement, key, value string, labels []prompb.Label, ts time.Time, stats *batchStats) (MetricKey, prompb.TimeSeries, bool) {
+	if s.infoFields != nil && !s.infoFields.Match(key) {
+		return 0, prompb.TimeSeries{}, false
+	}
+	if s.config.InfoMaxValueLength > 0 && len(value) > s.config.InfoMaxValueLength {
+		stats.skippedInfoValues++
+		return 0, prompb.TimeSeries{}, false
+	}
+
+	name, ok := prometheus.SanitizeMetricName(measurement + "_info")
+	if !ok {
+		return 0, prompb.TimeSeries{}, false
+	}
+
+	labelName, ok := s.labelName(key, stats)
+	if !ok || value == "" {
+		return 0, prompb.TimeSeries{}, false
+	}
+
+	// If there is a tag with the same name as the string field, discard
+	// the field and keep the tag.
+	if hasLabel(labelName, labels) {
+		return 0, prompb.TimeSeries{}, false
+	}
+
+	infoLabels := make([]prompb.Label, len(labels), len(labels)+1)
+	copy(infoLabels, labels)
+	infoLabels = append(infoLabels, prompb.Label{Name: labelName, Value: value})
+	sort.Slice(infoLabels, func(i, j int) bool {
+		return infoLabels[i].Name < infoLabels[j].Name
+	})
+
+	metrickey, promts := getPromTS(name, infoLabels, 1, ts)
+	return metrickey, promts, true
+}
@@ -3,6 +3,8 @@ package prometheusremotewrite
 import (
 	"bytes"
 	"fmt"
+	"log"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -634,14 +636,313 @@ rpc_duration_seconds_sum 17560473
 			},
 			expected: []byte(`
 			time_idle 42
+`),
+		},
+		{
+			name: "newest sample wins",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(10, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 43.0,
+					},
+					time.Unix(20, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 41.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{host="example.org"} 43
+`),
+		},
+		{
+			name: "string as info",
+			config: FormatConfig{
+				MetricSortOrder: SortMetrics,
+				StringHandling:  StringAsInfo,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+						"model":     "Xeon",
+						"vendor":    "Intel",
+						"host":      "discarded",
+						"empty":     "",
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{host="example.org"} 42
+cpu_info{host="example.org", model="Xeon"} 1
+cpu_info{host="example.org", vendor="Intel"} 1
+`),
+		},
+		{
+			name: "string as info limited",
+			config: FormatConfig{
+				MetricSortOrder:    SortMetrics,
+				StringHandling:     StringAsInfo,
+				InfoFields:         []string{"model", "mess*"},
+				InfoMaxValueLength: 8,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+						"model":     "Xeon",
+						"vendor":    "Intel",
+						"message":   "some free-text message",
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{host="example.org"} 42
+cpu_info{host="example.org", model="Xeon"} 1
+`),
+		},
+		{
+			name: "counter total suffix",
+			config: FormatConfig{
+				CounterTotalSuffix: true,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"net",
+					map[string]string{},
+					map[string]interface{}{
+						"bytes_recv": 42.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Counter,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{},
+					map[string]interface{}{
+						"http_requests_total": 42.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Counter,
+				),
+				testutil.MustMetric(
+					"mem",
+					map[string]string{},
+					map[string]interface{}{
+						"used": 42.0,
+					},
+					time.Unix(0, 0),
+					telegraf.Gauge,
+				),
+			},
+			expected: []byte(`
+http_requests_total 42
+mem_used 42
+net_bytes_recv_total 42
+`),
+		},
+		{
+			name: "drop large integers",
+			config: FormatConfig{
+				LargeIntHandling: DropLargeInts,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"net",
+					map[string]string{},
+					map[string]interface{}{
+						"bytes_recv":   uint64(1 << 60),
+						"bytes_sent":   int64(1 << 53),
+						"packets_recv": int64(-1 << 60),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+net_bytes_sent 9007199254740992
+`),
+		},
+		{
+			name: "max labels per series",
+			config: FormatConfig{
+				MaxLabelsPerSeries: 3,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu0",
+						"host": "example.org",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu0",
+						"host": "example.org",
+						"rack": "a1",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{cpu="cpu0", host="example.org"} 42
+`),
+		},
+		{
+			name: "max labels per series trim",
+			config: FormatConfig{
+				MaxLabelsPerSeries: 3,
+				MaxLabelsHandling:  TrimLabels,
+				LabelTrimOrder:     []string{"rack", "cpu"},
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu0",
+						"host": "example.org",
+						"rack": "a1",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu1",
+						"host": "example.org",
+						"zone": "z1",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"mem",
+					map[string]string{
+						"host": "example.org",
+						"zone": "z1",
+						"pool": "p1",
+					},
+					map[string]interface{}{
+						"used": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{cpu="cpu0", host="example.org"} 42
+cpu_time_idle{host="example.org", zone="z1"} 42
+`),
+		},
+		{
+			name: "duplicate label names keep last",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host.name": "a",
+						"host_name": "b",
+						"host_aa":   "c",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_idle{host_aa="c", host_name="b"} 42
+`),
+		},
+		{
+			name: "duplicate label names drop",
+			config: FormatConfig{
+				DuplicateLabelHandling: DropDuplicateLabels,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host.name": "a",
+						"host_name": "b",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host_name": "b",
+					},
+					map[string]interface{}{
+						"time_guest": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []byte(`
+cpu_time_guest{host_name="b"} 42
 `),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s, err := NewSerializer(FormatConfig{
-				MetricSortOrder: SortMetrics,
-				StringHandling:  tt.config.StringHandling,
+				MetricSortOrder:        SortMetrics,
+				StringHandling:         tt.config.StringHandling,
+				InfoFields:             tt.config.InfoFields,
+				InfoMaxValueLength:     tt.config.InfoMaxValueLength,
+				LargeIntHandling:       tt.config.LargeIntHandling,
+				CounterTotalSuffix:     tt.config.CounterTotalSuffix,
+				MaxLabelsPerSeries:     tt.config.MaxLabelsPerSeries,
+				MaxLabelsHandling:      tt.config.MaxLabelsHandling,
+				LabelTrimOrder:         tt.config.LabelTrimOrder,
+				DuplicateLabelHandling: tt.config.DuplicateLabelHandling,
 			})
 			require.NoError(t, err)
 			data, err := s.SerializeBatch(tt.metrics)
@@ -656,6 +957,338 @@ rpc_duration_seconds_sum 17560473
 	}
 }
 
+func TestRemoteWriteSerializeNameTag(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"__name__": "foo",
+			"host":     "example.org",
+		},
+		map[string]interface{}{
+			"time_idle": 42.0,
+			"__name__":  "bar",
+		},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name     string
+		handling NameLabelHandling
+		expected []prompb.Label
+		log      string
+	}{
+		{
+			name:     "strip",
+			handling: StripNameLabel,
+			expected: []prompb.Label{
+				{Name: "host", Value: "example.org"},
+				{Name: "__name__", Value: "cpu_time_idle"},
+			},
+			log: "W! Serializer [prometheusremotewrite] discarded 2 tags or fields named __name__",
+		},
+		{
+			name:     "rename",
+			handling: RenameNameLabel,
+			expected: []prompb.Label{
+				{Name: "exported___name__", Value: "foo"},
+				{Name: "host", Value: "example.org"},
+				{Name: "__name__", Value: "cpu_time_idle"},
+			},
+			log: "W! Serializer [prometheusremotewrite] renamed 2 tags or fields named __name__ to exported___name__",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(FormatConfig{
+				StringHandling:    StringAsLabel,
+				NameLabelHandling: tt.handling,
+			})
+			require.NoError(t, err)
+
+			var data []byte
+			output := captureLog(func() {
+				data, err = s.Serialize(m)
+			})
+			require.NoError(t, err)
+			require.Contains(t, output, tt.log)
+
+			protobuff, err := snappy.Decode(nil, data)
+			require.NoError(t, err)
+			var req prompb.WriteRequest
+			require.NoError(t, req.Unmarshal(protobuff))
+
+			require.Len(t, req.Timeseries, 1)
+			require.Equal(t, tt.expected, req.Timeseries[0].Labels)
+		})
+	}
+}
+
+func TestRemoteWriteSerializeFutureTimestamps(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"time_idle": 42.0},
+			time.Unix(100, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"cpu": "cpu1"},
+			map[string]interface{}{"time_idle": 42.0},
+			time.Unix(200, 0),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		handling FutureTimestampHandling
+		expected []int64
+		log      string
+	}{
+		{
+			name:     "clamp",
+			handling: ClampFutureTimestamps,
+			expected: []int64{100000, 110000},
+			log:      "W! Serializer [prometheusremotewrite] clamped the timestamp of 1 metrics too far in the future",
+		},
+		{
+			name:     "drop",
+			handling: DropFutureTimestamps,
+			expected: []int64{100000},
+			log:      "W! Serializer [prometheusremotewrite] dropped 1 metrics with a timestamp too far in the future",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(FormatConfig{
+				MetricSortOrder:         SortMetrics,
+				MaxFutureSkew:           10 * time.Second,
+				FutureTimestampHandling: tt.handling,
+			})
+			require.NoError(t, err)
+			s.now = func() time.Time { return time.Unix(100, 0) }
+
+			var data []byte
+			output := captureLog(func() {
+				data, err = s.SerializeBatch(metrics)
+			})
+			require.NoError(t, err)
+			require.Contains(t, output, tt.log)
+
+			protobuff, err := snappy.Decode(nil, data)
+			require.NoError(t, err)
+			var req prompb.WriteRequest
+			require.NoError(t, req.Unmarshal(protobuff))
+
+			var actual []int64
+			for _, ts := range req.Timeseries {
+				for _, sample := range ts.Samples {
+					actual = append(actual, sample.Timestamp)
+				}
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestRemoteWriteSerializeLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  FormatConfig
+		metrics []telegraf.Metric
+		logs    []string
+	}{
+		{
+			name: "large int convert",
+			config: FormatConfig{
+				LargeIntHandling: ConvertLargeInts,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"disk",
+					map[string]string{},
+					map[string]interface{}{
+						"bytes": uint64(1<<53 + 1),
+						"small": int64(42),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] lost precision converting 1 integers larger than 2^53",
+			},
+		},
+		{
+			name: "large int drop",
+			config: FormatConfig{
+				LargeIntHandling: DropLargeInts,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"disk",
+					map[string]string{},
+					map[string]interface{}{
+						"bytes": uint64(1<<53 + 1),
+						"small": int64(42),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] dropped 1 integers larger than 2^53",
+			},
+		},
+		{
+			name: "info value length",
+			config: FormatConfig{
+				StringHandling:     StringAsInfo,
+				InfoMaxValueLength: 8,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"model":   "Xeon",
+						"message": "some free-text message",
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] skipped 1 string fields too long for an info series",
+			},
+		},
+		{
+			name: "max labels trim",
+			config: FormatConfig{
+				MaxLabelsPerSeries: 3,
+				MaxLabelsHandling:  TrimLabels,
+				LabelTrimOrder:     []string{"rack"},
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu0",
+						"host": "example.org",
+						"rack": "a1",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"cpu":  "cpu0",
+						"host": "example.org",
+						"zone": "z1",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] removed labels from 1 metrics exceeding the label limit",
+				"W! Serializer [prometheusremotewrite] dropped 1 series exceeding the label limit",
+			},
+		},
+		{
+			name: "duplicate labels keep last",
+			config: FormatConfig{
+				DuplicateLabelHandling: KeepLastDuplicateLabel,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host.name": "a",
+						"host_name": "b",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] discarded 1 labels with a duplicate name after sanitizing",
+			},
+		},
+		{
+			name: "duplicate labels drop",
+			config: FormatConfig{
+				DuplicateLabelHandling: DropDuplicateLabels,
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{
+						"host.name": "a",
+						"host_name": "b",
+					},
+					map[string]interface{}{
+						"time_idle": 42.0,
+					},
+					time.Unix(0, 0),
+				),
+			},
+			logs: []string{
+				"W! Serializer [prometheusremotewrite] dropped 1 metrics with duplicate label names after sanitizing",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(tt.config)
+			require.NoError(t, err)
+
+			output := captureLog(func() {
+				_, err = s.SerializeBatch(tt.metrics)
+			})
+			require.NoError(t, err)
+			for _, line := range tt.logs {
+				require.Contains(t, output, line)
+			}
+		})
+	}
+}
+
+func BenchmarkRemoteWriteSerializeBatch(b *testing.B) {
+	metrics := make([]telegraf.Metric, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"cpu":  fmt.Sprintf("cpu%d", i),
+				"host": "example.org",
+			},
+			map[string]interface{}{
+				"time_user":   42.0,
+				"time_system": 42.0,
+				"time_idle":   42.0,
+				"time_iowait": 42.0,
+				"time_guest":  42.0,
+			},
+			time.Unix(0, 0),
+		))
+	}
+
+	s, err := NewSerializer(FormatConfig{})
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, err := s.SerializeBatch(metrics)
+		require.NoError(b, err)
+	}
+}
+
 func prompbToText(data []byte) ([]byte, error) {
 	var buf = bytes.Buffer{}
 	protobuff, err := snappy.Decode(nil, data)
@@ -698,3 +1331,12 @@ func protoToSamples(req *prompb.WriteRequest) model.Samples {
 	}
 	return samples
 }
+
+// captureLog returns the output of the standard logger while running f.
+func captureLog(f func()) string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	f()
+	return buf.String()
+}
@@ -117,6 +117,45 @@ type Config struct {
 	// Output string fields as metric labels; when false string fields are
 	// discarded.
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
+
+	// Output string fields as info-style series with the string value as a
+	// label; only supported by the prometheusremotewrite format.
+	PrometheusStringAsInfo bool `toml:"prometheus_string_as_info"`
+
+	// String fields written as info series and the maximum length of their
+	// values; only supported by the prometheusremotewrite format.
+	PrometheusInfoFields         []string `toml:"prometheus_info_fields"`
+	PrometheusInfoMaxValueLength int      `toml:"prometheus_info_max_value_length"`
+
+	// Maximum number of labels per series and how to handle series with more
+	// labels, either "drop" or "trim" with the labels in the trim order
+	// removed first; only supported by the prometheusremotewrite format.
+	PrometheusMaxLabelsPerSeries int      `toml:"prometheus_max_labels_per_series"`
+	PrometheusMaxLabelsPolicy    string   `toml:"prometheus_max_labels_policy"`
+	PrometheusLabelTrimOrder     []string `toml:"prometheus_label_trim_order"`
+
+	// How to handle integers that cannot be represented exactly as a float
+	// sample value, either "convert" or "drop"; only supported by the
+	// prometheusremotewrite format.
+	PrometheusLargeIntPolicy string `toml:"prometheus_large_int_policy"`
+
+	// Maximum time a metric timestamp may be ahead of the current time and
+	// how to handle metrics beyond it, either "clamp" or "drop"; only
+	// supported by the prometheusremotewrite format.
+	PrometheusMaxFutureSkew         time.Duration `toml:"prometheus_max_future_skew"`
+	PrometheusFutureTimestampPolicy string        `toml:"prometheus_future_timestamp_policy"`
+
+	// Append "_total" to the name of counter metrics; only supported by the
+	// prometheusremotewrite format.
+	PrometheusCounterTotalSuffix bool `toml:"prometheus_counter_total_suffix"`
+
+	// How to handle labels sharing a name after sanitization, either "last"
+	// or "drop"; only supported by the prometheusremotewrite format.
+	PrometheusDuplicateLabelPolicy string `toml:"prometheus_duplicate_label_policy"`
+
+	// How to handle tags and string fields named "__name__", either "strip"
+	// or "rename"; only supported by the prometheusremotewrite format.
+	PrometheusNameLabelPolicy string `toml:"prometheus_name_label_policy"`
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -156,14 +195,82 @@ func NewPrometheusRemoteWriteSerializer(config *Config) (Serializer, error) {
 		sortMetrics = prometheusremotewrite.SortMetrics
 	}
 
+	if config.PrometheusStringAsLabel && config.PrometheusStringAsInfo {
+		return nil, fmt.Errorf("prometheus_string_as_label and prometheus_string_as_info are mutually exclusive")
+	}
+
 	stringAsLabels := prometheusremotewrite.DiscardStrings
 	if config.PrometheusStringAsLabel {
 		stringAsLabels = prometheusremotewrite.StringAsLabel
 	}
+	if config.PrometheusStringAsInfo {
+		stringAsLabels = prometheusremotewrite.StringAsInfo
+	}
+
+	var largeInts prometheusremotewrite.LargeIntHandling
+	switch config.PrometheusLargeIntPolicy {
+	case "", "convert":
+		largeInts = prometheusremotewrite.ConvertLargeInts
+	case "drop":
+		largeInts = prometheusremotewrite.DropLargeInts
+	default:
+		return nil, fmt.Errorf("invalid prometheus_large_int_policy %q", config.PrometheusLargeIntPolicy)
+	}
+
+	var futureTimestamps prometheusremotewrite.FutureTimestampHandling
+	switch config.PrometheusFutureTimestampPolicy {
+	case "", "clamp":
+		futureTimestamps = prometheusremotewrite.ClampFutureTimestamps
+	case "drop":
+		futureTimestamps = prometheusremotewrite.DropFutureTimestamps
+	default:
+		return nil, fmt.Errorf("invalid prometheus_future_timestamp_policy %q", config.PrometheusFutureTimestampPolicy)
+	}
+
+	var duplicateLabels prometheusremotewrite.DuplicateLabelHandling
+	switch config.PrometheusDuplicateLabelPolicy {
+	case "", "last":
+		duplicateLabels = prometheusremotewrite.KeepLastDuplicateLabel
+	case "drop":
+		duplicateLabels = prometheusremotewrite.DropDuplicateLabels
+	default:
+		return nil, fmt.Errorf("invalid prometheus_duplicate_label_policy %q", config.PrometheusDuplicateLabelPolicy)
+	}
+
+	var maxLabels prometheusremotewrite.MaxLabelsHandling
+	switch config.PrometheusMaxLabelsPolicy {
+	case "", "drop":
+		maxLabels = prometheusremotewrite.DropSeries
+	case "trim":
+		maxLabels = prometheusremotewrite.TrimLabels
+	default:
+		return nil, fmt.Errorf("invalid prometheus_max_labels_policy %q", config.PrometheusMaxLabelsPolicy)
+	}
+
+	var nameLabels prometheusremotewrite.NameLabelHandling
+	switch config.PrometheusNameLabelPolicy {
+	case "", "strip":
+		nameLabels = prometheusremotewrite.StripNameLabel
+	case "rename":
+		nameLabels = prometheusremotewrite.RenameNameLabel
+	default:
+		return nil, fmt.Errorf("invalid prometheus_name_label_policy %q", config.PrometheusNameLabelPolicy)
+	}
 
 	return prometheusremotewrite.NewSerializer(prometheusremotewrite.FormatConfig{
-		MetricSortOrder: sortMetrics,
-		StringHandling:  stringAsLabels,
+		MetricSortOrder:         sortMetrics,
+		StringHandling:          stringAsLabels,
+		LargeIntHandling:        largeInts,
+		CounterTotalSuffix:      config.PrometheusCounterTotalSuffix,
+		MaxFutureSkew:           config.PrometheusMaxFutureSkew,
+		FutureTimestampHandling: futureTimestamps,
+		InfoFields:              config.PrometheusInfoFields,
+		InfoMaxValueLength:      config.PrometheusInfoMaxValueLength,
+		MaxLabelsPerSeries:      config.PrometheusMaxLabelsPerSeries,
+		MaxLabelsHandling:       maxLabels,
+		LabelTrimOrder:          config.PrometheusLabelTrimOrder,
+		DuplicateLabelHandling:  duplicateLabels,
+		NameLabelHandling:       nameLabels,
 	})
 }
 